@@ -0,0 +1,21 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package plugin
+
+import (
+	"context"
+
+	"github.com/harness/gitness/types"
+)
+
+// Channels returns the sync health of every configured plugin channel - last
+// sync time, last error, and plugin counts - so operators can see background
+// sync status without it being lost across restarts.
+func (c *Controller) Channels(ctx context.Context) ([]*types.PluginChannel, error) {
+	if err := c.authorize(ctx, permissionPluginView); err != nil {
+		return nil, err
+	}
+	return c.channelStore.List(ctx)
+}
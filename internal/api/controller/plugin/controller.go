@@ -0,0 +1,63 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/harness/gitness/internal/auth/authz"
+	"github.com/harness/gitness/internal/pipeline/plugin"
+	"github.com/harness/gitness/internal/store"
+)
+
+// permission identifies an action gated by the authorizer. Plugins are a
+// system-wide resource (not scoped to a space or repo), so a plain action
+// name is enough - there's no resource identity narrower than "plugins" to
+// check against.
+type permission string
+
+const (
+	// permissionPluginView gates read-only plugin endpoints.
+	permissionPluginView permission = "plugin_view"
+	// permissionPluginManage gates endpoints that change plugin state.
+	permissionPluginManage permission = "plugin_manage"
+)
+
+// Controller exposes plugin lifecycle operations (enable/disable/remove/
+// inspect/list) to the REST API, backed by the pipeline plugin manager.
+type Controller struct {
+	authorizer    authz.Authorizer
+	pluginStore   store.PluginStore
+	channelStore  store.PluginChannelStore
+	pluginManager *plugin.PluginManager
+}
+
+func NewController(
+	authorizer authz.Authorizer,
+	pluginStore store.PluginStore,
+	channelStore store.PluginChannelStore,
+	pluginManager *plugin.PluginManager,
+) *Controller {
+	return &Controller{
+		authorizer:    authorizer,
+		pluginStore:   pluginStore,
+		channelStore:  channelStore,
+		pluginManager: pluginManager,
+	}
+}
+
+// authorize refuses the request unless the caller holds perm, so every
+// plugin endpoint is gated the same way regardless of which one is hit.
+func (c *Controller) authorize(ctx context.Context, perm permission) error {
+	ok, err := c.authorizer.Check(ctx, string(perm))
+	if err != nil {
+		return fmt.Errorf("could not check %q permission: %w", perm, err)
+	}
+	if !ok {
+		return fmt.Errorf("not authorized: requires %q permission", perm)
+	}
+	return nil
+}
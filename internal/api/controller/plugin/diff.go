@@ -0,0 +1,90 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/harness/gitness/types"
+)
+
+// Diff returns how declared privileges changed between two versions of a
+// plugin, so the UI can prompt for confirmation before a pipeline is allowed
+// to reference the new version.
+func (c *Controller) Diff(ctx context.Context, name, fromVersion, toVersion string) (*types.PluginPrivilegeDiff, error) {
+	if err := c.authorize(ctx, permissionPluginView); err != nil {
+		return nil, err
+	}
+
+	from, err := c.pluginManager.Inspect(ctx, name, fromVersion)
+	if err != nil {
+		return nil, fmt.Errorf("could not find plugin %s:%s: %w", name, fromVersion, err)
+	}
+	to, err := c.pluginManager.Inspect(ctx, name, toVersion)
+	if err != nil {
+		return nil, fmt.Errorf("could not find plugin %s:%s: %w", name, toVersion, err)
+	}
+
+	fromPriv, err := decodePrivileges(from)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode privileges for %s:%s: %w", name, fromVersion, err)
+	}
+	toPriv, err := decodePrivileges(to)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode privileges for %s:%s: %w", name, toVersion, err)
+	}
+
+	return diffPrivileges(fromPriv, toPriv), nil
+}
+
+// decodePrivileges unmarshals the JSON-encoded privileges stored on a plugin.
+func decodePrivileges(p *types.Plugin) (types.PluginPrivileges, error) {
+	var privileges types.PluginPrivileges
+	if p.Privileges == "" {
+		return privileges, nil
+	}
+	if err := json.Unmarshal([]byte(p.Privileges), &privileges); err != nil {
+		return privileges, err
+	}
+	return privileges, nil
+}
+
+// diffPrivileges reports which privileges were gained and lost going from
+// one version's privileges to another's.
+func diffPrivileges(from, to types.PluginPrivileges) *types.PluginPrivilegeDiff {
+	return &types.PluginPrivilegeDiff{
+		Added: types.PluginPrivileges{
+			NetworkEgress: sliceDiff(to.NetworkEgress, from.NetworkEgress),
+			Mounts:        sliceDiff(to.Mounts, from.Mounts),
+			Secrets:       sliceDiff(to.Secrets, from.Secrets),
+			Env:           sliceDiff(to.Env, from.Env),
+			RunAsRoot:     to.RunAsRoot && !from.RunAsRoot,
+		},
+		Removed: types.PluginPrivileges{
+			NetworkEgress: sliceDiff(from.NetworkEgress, to.NetworkEgress),
+			Mounts:        sliceDiff(from.Mounts, to.Mounts),
+			Secrets:       sliceDiff(from.Secrets, to.Secrets),
+			Env:           sliceDiff(from.Env, to.Env),
+			RunAsRoot:     from.RunAsRoot && !to.RunAsRoot,
+		},
+	}
+}
+
+// sliceDiff returns the entries in a that aren't in b.
+func sliceDiff(a, b []string) []string {
+	inB := make(map[string]struct{}, len(b))
+	for _, v := range b {
+		inB[v] = struct{}{}
+	}
+	var out []string
+	for _, v := range a {
+		if _, ok := inB[v]; !ok {
+			out = append(out, v)
+		}
+	}
+	return out
+}
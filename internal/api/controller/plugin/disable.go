@@ -0,0 +1,20 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package plugin
+
+import (
+	"context"
+
+	"github.com/harness/gitness/types"
+)
+
+// Disable disables a plugin version so pipeline resolution will refuse to
+// use it, without removing it from the store.
+func (c *Controller) Disable(ctx context.Context, name, version string) (*types.Plugin, error) {
+	if err := c.authorize(ctx, permissionPluginManage); err != nil {
+		return nil, err
+	}
+	return c.pluginManager.Disable(ctx, name, version)
+}
@@ -0,0 +1,19 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package plugin
+
+import (
+	"context"
+
+	"github.com/harness/gitness/types"
+)
+
+// Enable enables a plugin version, allowing pipelines to resolve it again.
+func (c *Controller) Enable(ctx context.Context, name, version string) (*types.Plugin, error) {
+	if err := c.authorize(ctx, permissionPluginManage); err != nil {
+		return nil, err
+	}
+	return c.pluginManager.Enable(ctx, name, version)
+}
@@ -0,0 +1,19 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package plugin
+
+import (
+	"context"
+
+	"github.com/harness/gitness/types"
+)
+
+// Find returns the full record for a single plugin version.
+func (c *Controller) Find(ctx context.Context, name, version string) (*types.Plugin, error) {
+	if err := c.authorize(ctx, permissionPluginView); err != nil {
+		return nil, err
+	}
+	return c.pluginManager.Inspect(ctx, name, version)
+}
@@ -0,0 +1,19 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package plugin
+
+import (
+	"context"
+
+	"github.com/harness/gitness/types"
+)
+
+// List returns the plugins matching the given filter.
+func (c *Controller) List(ctx context.Context, filter types.PluginFilter) ([]*types.Plugin, error) {
+	if err := c.authorize(ctx, permissionPluginView); err != nil {
+		return nil, err
+	}
+	return c.pluginManager.List(ctx, filter)
+}
@@ -0,0 +1,17 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package plugin
+
+import (
+	"context"
+)
+
+// Remove deletes a plugin version from the store entirely.
+func (c *Controller) Remove(ctx context.Context, name, version string) error {
+	if err := c.authorize(ctx, permissionPluginManage); err != nil {
+		return err
+	}
+	return c.pluginManager.Remove(ctx, name, version)
+}
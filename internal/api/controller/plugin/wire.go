@@ -0,0 +1,26 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package plugin
+
+import (
+	"github.com/google/wire"
+	"github.com/harness/gitness/internal/auth/authz"
+	"github.com/harness/gitness/internal/pipeline/plugin"
+	"github.com/harness/gitness/internal/store"
+)
+
+// WireSet provides a wire set for this package.
+var WireSet = wire.NewSet(
+	NewController,
+)
+
+func ProvideController(
+	authorizer authz.Authorizer,
+	pluginStore store.PluginStore,
+	channelStore store.PluginChannelStore,
+	pluginManager *plugin.PluginManager,
+) *Controller {
+	return NewController(authorizer, pluginStore, channelStore, pluginManager)
+}
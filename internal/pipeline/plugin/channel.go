@@ -0,0 +1,168 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/harness/gitness/types"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultChannelSyncInterval is used when CI.PluginChannelSyncInterval isn't
+// configured.
+const defaultChannelSyncInterval = time.Hour
+
+// channelIndex is the document a plugin channel URL is expected to serve:
+// an enumeration of plugin packages, their available versions, and a source
+// URI to fetch each one from (fed into NewPluginSource).
+type channelIndex struct {
+	Plugins []channelPlugin `json:"plugins"`
+}
+
+type channelPlugin struct {
+	Name     string           `json:"name"`
+	Versions []channelVersion `json:"versions"`
+}
+
+type channelVersion struct {
+	Version  string `json:"version"`
+	Checksum string `json:"checksum"`
+	URL      string `json:"url"`
+}
+
+// runChannelSync polls every configured plugin channel on a fixed interval,
+// logging (rather than failing) individual sync errors so one bad channel
+// can't block the others, until ctx is cancelled via Close.
+func (m *PluginManager) runChannelSync(ctx context.Context) {
+	interval := m.config.CI.PluginChannelSyncInterval
+	if interval <= 0 {
+		interval = defaultChannelSyncInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.SyncChannels(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncChannels polls every configured plugin channel once, diffing against
+// the DB using each channel's ETag and per-plugin checksums so unchanged
+// channels and plugins are never re-downloaded.
+func (m *PluginManager) SyncChannels(ctx context.Context) {
+	for _, url := range m.config.CI.PluginChannels {
+		if err := m.syncChannel(ctx, url); err != nil {
+			log.Warn().Str("channel", url).Err(err).Msg("could not sync plugin channel")
+		}
+	}
+}
+
+// syncChannel fetches a single channel's index - skipping the body entirely
+// if the index is unchanged since the last sync - and upserts any plugin
+// version whose checksum changed.
+func (m *PluginManager) syncChannel(ctx context.Context, url string) error {
+	status, err := m.channelStore.Find(ctx, url)
+	if err != nil {
+		status = &types.PluginChannel{URL: url}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("could not build request: %w", err)
+	}
+	if status.ETag != "" {
+		req.Header.Set("If-None-Match", status.ETag)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		status.LastError = err.Error()
+		m.persistChannelStatus(ctx, status)
+		return fmt.Errorf("could not fetch channel index: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		status.LastError = ""
+		m.persistChannelStatus(ctx, status)
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		status.LastError = fmt.Sprintf("unexpected status %d fetching channel index", resp.StatusCode)
+		m.persistChannelStatus(ctx, status)
+		return errors.New(status.LastError)
+	}
+
+	var index channelIndex
+	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
+		status.LastError = err.Error()
+		m.persistChannelStatus(ctx, status)
+		return fmt.Errorf("could not decode channel index: %w", err)
+	}
+
+	pending := m.fetchChangedVersions(ctx, url, index)
+
+	if err := m.upsertPending(ctx, pending); err != nil {
+		status.LastError = err.Error()
+		m.persistChannelStatus(ctx, status)
+		return fmt.Errorf("could not upsert plugins from channel: %w", err)
+	}
+
+	status.ETag = resp.Header.Get("ETag")
+	status.LastError = ""
+	status.PluginCount = len(index.Plugins)
+	m.persistChannelStatus(ctx, status)
+	return nil
+}
+
+// fetchChangedVersions fetches the artifact for every plugin version in the
+// index whose checksum doesn't match what's already in the store, skipping
+// unchanged ones without a download.
+func (m *PluginManager) fetchChangedVersions(ctx context.Context, channelURL string, index channelIndex) []*pendingPlugin {
+	var pending []*pendingPlugin
+	for _, p := range index.Plugins {
+		for _, v := range p.Versions {
+			if existing, err := m.pluginStore.Find(ctx, p.Name, v.Version); err == nil &&
+				v.Checksum != "" && existing.Checksum == v.Checksum {
+				continue
+			}
+
+			src, err := NewPluginSource(v.URL)
+			if err != nil {
+				log.Warn().Str("channel", channelURL).Str("plugin", p.Name).Str("version", v.Version).
+					Err(err).Msg("could not build plugin source")
+				continue
+			}
+			fetched, err := src.Fetch(ctx)
+			if err != nil {
+				log.Warn().Str("channel", channelURL).Str("plugin", p.Name).Str("version", v.Version).
+					Err(err).Msg("could not fetch plugin artifact")
+				continue
+			}
+			pending = append(pending, fetched...)
+		}
+	}
+	return pending
+}
+
+// persistChannelStatus stamps the sync time and writes channel status,
+// logging rather than failing the sync if the write itself fails.
+func (m *PluginManager) persistChannelStatus(ctx context.Context, status *types.PluginChannel) {
+	status.LastSyncedAt = time.Now().Unix()
+	if err := m.channelStore.Upsert(ctx, status); err != nil {
+		log.Warn().Str("channel", status.URL).Err(err).Msg("could not persist plugin channel status")
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/harness/gitness/types"
+)
+
+// fakePluginChannelStore is an in-memory store.PluginChannelStore, keyed by
+// URL, used to exercise syncChannel/runChannelSync without a real database.
+type fakePluginChannelStore struct {
+	channels map[string]*types.PluginChannel
+}
+
+func newFakePluginChannelStore() *fakePluginChannelStore {
+	return &fakePluginChannelStore{channels: map[string]*types.PluginChannel{}}
+}
+
+func (s *fakePluginChannelStore) Find(_ context.Context, url string) (*types.PluginChannel, error) {
+	c, ok := s.channels[url]
+	if !ok {
+		return nil, fmt.Errorf("channel %s not found", url)
+	}
+	return c, nil
+}
+
+func (s *fakePluginChannelStore) List(_ context.Context) ([]*types.PluginChannel, error) {
+	out := make([]*types.PluginChannel, 0, len(s.channels))
+	for _, c := range s.channels {
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (s *fakePluginChannelStore) Upsert(_ context.Context, channel *types.PluginChannel) error {
+	s.channels[channel.URL] = channel
+	return nil
+}
+
+// TestSyncChannelHonorsETag is a regression test: a channel whose index is
+// unchanged since the last sync (the server replies 304 to If-None-Match)
+// must be left alone rather than re-parsed and re-upserted.
+func TestSyncChannelHonorsETag(t *testing.T) {
+	var requests, notModified int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, `{"plugins":[]}`)
+	}))
+	defer srv.Close()
+
+	channelStore := newFakePluginChannelStore()
+	m := &PluginManager{
+		config:       &types.Config{},
+		pluginStore:  newFakePluginStore(),
+		channelStore: channelStore,
+	}
+
+	if err := m.syncChannel(context.Background(), srv.URL); err != nil {
+		t.Fatalf("unexpected error on first sync: %v", err)
+	}
+	status, err := channelStore.Find(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.ETag != `"v1"` {
+		t.Fatalf("expected ETag to be persisted, got %q", status.ETag)
+	}
+
+	if err := m.syncChannel(context.Background(), srv.URL); err != nil {
+		t.Fatalf("unexpected error on second sync: %v", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Fatalf("expected exactly 2 requests against the channel URL, got %d", got)
+	}
+	if got := atomic.LoadInt32(&notModified); got != 1 {
+		t.Fatalf("expected the second sync to send If-None-Match and get a 304, got %d 304 responses", got)
+	}
+	status, err = channelStore.Find(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.LastError != "" {
+		t.Fatalf("expected a 304 response to clear LastError, got %q", status.LastError)
+	}
+}
+
+// TestFetchChangedVersionsSkipsUnchangedChecksum is a regression test: a
+// plugin version whose checksum matches what's already in the store must be
+// skipped without attempting to build a source or download it.
+func TestFetchChangedVersionsSkipsUnchangedChecksum(t *testing.T) {
+	var downloads int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&downloads, 1)
+	}))
+	defer srv.Close()
+
+	existing := &types.Plugin{UID: "docker", Version: "1.0.0", Checksum: "abc123"}
+	m := &PluginManager{
+		config:      &types.Config{},
+		pluginStore: newFakePluginStore(existing),
+	}
+
+	index := channelIndex{Plugins: []channelPlugin{
+		{
+			Name: "docker",
+			Versions: []channelVersion{
+				// Unchanged checksum: must be skipped. The URL points at a
+				// server that counts hits, to prove no fetch is attempted.
+				{Version: "1.0.0", Checksum: "abc123", URL: srv.URL + "/plugins.zip"},
+			},
+		},
+	}}
+
+	pending := m.fetchChangedVersions(context.Background(), "channel", index)
+	if len(pending) != 0 {
+		t.Fatalf("expected unchanged version to be skipped, got %d pending entries", len(pending))
+	}
+	if got := atomic.LoadInt32(&downloads); got != 0 {
+		t.Fatalf("expected no download for an unchanged version, got %d requests", got)
+	}
+}
+
+// TestRunChannelSyncStopsOnClose is a regression test for the claim that the
+// background channel sync goroutine is stoppable: once Close cancels the
+// context, no further sync should occur.
+func TestRunChannelSyncStopsOnClose(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"plugins":[]}`)
+	}))
+	defer srv.Close()
+
+	m := NewPluginManager(
+		&types.Config{CI: types.CI{
+			PluginChannels:            []string{srv.URL},
+			PluginChannelSyncInterval: 10 * time.Millisecond,
+		}},
+		newFakePluginStore(),
+		newFakePluginChannelStore(),
+	)
+
+	// Let a few sync ticks run, then stop the goroutine.
+	time.Sleep(50 * time.Millisecond)
+	m.Close()
+	// Give any sync already in flight at the moment of Close time to finish,
+	// then take the settled count.
+	time.Sleep(20 * time.Millisecond)
+	settled := atomic.LoadInt32(&requests)
+	if settled == 0 {
+		t.Fatalf("expected at least one sync to have happened before Close")
+	}
+
+	// Give the goroutine time to keep ticking if it wasn't actually stopped.
+	time.Sleep(100 * time.Millisecond)
+	afterClose := atomic.LoadInt32(&requests)
+	if afterClose != settled {
+		t.Fatalf("expected no further syncs after Close, got %d settled and %d after waiting", settled, afterClose)
+	}
+}
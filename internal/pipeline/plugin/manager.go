@@ -5,18 +5,13 @@
 package plugin
 
 import (
-	"archive/zip"
-	"bytes"
 	"context"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
-	"path/filepath"
 
 	"github.com/harness/gitness/internal/store"
 	"github.com/harness/gitness/types"
 
+	"github.com/blang/semver/v4"
 	v1yaml "github.com/drone/spec/dist/go"
 	"github.com/drone/spec/dist/go/parse"
 	"github.com/rs/zerolog/log"
@@ -26,21 +21,41 @@ import (
 type LookupFunc func(name, kind, typ, version string) (*v1yaml.Config, error)
 
 type PluginManager struct {
-	config      *types.Config
-	pluginStore store.PluginStore
+	config       *types.Config
+	pluginStore  store.PluginStore
+	channelStore store.PluginChannelStore
+	close        context.CancelFunc
 }
 
 func NewPluginManager(
 	config *types.Config,
 	pluginStore store.PluginStore,
+	channelStore store.PluginChannelStore,
 ) *PluginManager {
-	return &PluginManager{
-		config:      config,
-		pluginStore: pluginStore,
+	m := &PluginManager{
+		config:       config,
+		pluginStore:  pluginStore,
+		channelStore: channelStore,
+	}
+	if len(config.CI.PluginChannels) > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.close = cancel
+		go m.runChannelSync(ctx)
+	}
+	return m
+}
+
+// Close stops the background channel sync goroutine, if one was started. It
+// is safe to call even when no channels are configured.
+func (m *PluginManager) Close() {
+	if m.close != nil {
+		m.close()
 	}
 }
 
 // GetLookupFn returns a lookup function for plugins which can be used in the resolver.
+// version may be an exact semver (e.g. "1.2.3") or a semver range
+// (e.g. "^1.2", ">=2.0.0 <3") - the highest enabled version satisfying it wins.
 func (m *PluginManager) GetLookupFn() LookupFunc {
 	return func(name, kind, typ, version string) (*v1yaml.Config, error) {
 		if kind != "plugin" {
@@ -49,7 +64,7 @@ func (m *PluginManager) GetLookupFn() LookupFunc {
 		if typ != "step" {
 			return nil, fmt.Errorf("only step plugins supported")
 		}
-		plugin, err := m.pluginStore.Find(context.Background(), name, version)
+		plugin, err := m.resolveVersion(context.Background(), name, version)
 		if err != nil {
 			return nil, fmt.Errorf("could not lookup plugin: %w", err)
 		}
@@ -63,168 +78,277 @@ func (m *PluginManager) GetLookupFn() LookupFunc {
 	}
 }
 
-// Populate fetches plugins information from an external source or a local zip
-// and populates in the DB.
-func (m *PluginManager) Populate(ctx context.Context) error {
-	path := m.config.CI.PluginsZipPath
-	if path == "" {
-		return fmt.Errorf("plugins path not provided to read schemas from")
+// resolveVersion returns the highest enabled version of the named plugin
+// that satisfies the given semver range.
+func (m *PluginManager) resolveVersion(ctx context.Context, name, rng string) (*types.Plugin, error) {
+	versions, err := m.pluginStore.ListVersions(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("could not list versions for plugin %s: %w", name, err)
 	}
+	plugin, err := highestMatching(versions, rng)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve plugin %s@%s: %w", name, rng, err)
+	}
+	return plugin, nil
+}
 
-	var zipFile *zip.ReadCloser
-	if _, err := os.Stat(path); err != nil { // local path doesn't exist - must be a remote link
-		// Download zip file locally
-		f, err := os.CreateTemp(os.TempDir(), "plugins.zip")
-		if err != nil {
-			return fmt.Errorf("could not create temp file: %w", err)
+// highestMatching returns the highest enabled version among candidates that
+// satisfies the given semver range. Candidates whose version isn't valid
+// semver are ignored.
+func highestMatching(candidates []*types.Plugin, rng string) (*types.Plugin, error) {
+	translated, err := translateRange(rng)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version range %q: %w", rng, err)
+	}
+	r, err := semver.ParseRange(translated)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version range %q: %w", rng, err)
+	}
+	var best *types.Plugin
+	var bestVer semver.Version
+	for _, c := range candidates {
+		if !c.Enabled {
+			continue
 		}
-		defer os.Remove(f.Name())
-		err = downloadZip(path, f.Name())
+		v, err := semver.Parse(c.Version)
 		if err != nil {
-			return fmt.Errorf("could not download remote zip: %w", err)
+			continue
+		}
+		if !r(v) {
+			continue
+		}
+		if best == nil || v.GT(bestVer) {
+			best, bestVer = c, v
 		}
-		path = f.Name()
 	}
-	// open up a zip reader for the file
-	zipFile, err := zip.OpenReader(path)
-	if err != nil {
-		return fmt.Errorf("could not open zip for reading: %w", err)
+	if best == nil {
+		return nil, fmt.Errorf("no enabled version satisfies range %q", rng)
 	}
-	defer zipFile.Close()
+	return best, nil
+}
 
-	// upsert any new plugins.
-	err = m.traverseAndUpsertPlugins(ctx, zipFile)
+// Enable marks a plugin version as enabled so it can once again be resolved
+// when a pipeline references it.
+func (m *PluginManager) Enable(ctx context.Context, name, version string) (*types.Plugin, error) {
+	return m.setEnabled(ctx, name, version, true)
+}
+
+// Disable marks a plugin version as disabled so pipeline resolution will
+// refuse to look it up, without removing it (or its history) from the store.
+func (m *PluginManager) Disable(ctx context.Context, name, version string) (*types.Plugin, error) {
+	return m.setEnabled(ctx, name, version, false)
+}
+
+// setEnabled flips the enabled state of a plugin version and persists it.
+func (m *PluginManager) setEnabled(
+	ctx context.Context,
+	name, version string,
+	enabled bool,
+) (*types.Plugin, error) {
+	plugin, err := m.pluginStore.Find(ctx, name, version)
 	if err != nil {
-		return fmt.Errorf("could not upsert plugins: %w", err)
+		return nil, fmt.Errorf("could not find plugin %s:%s: %w", name, version, err)
+	}
+	if plugin.Enabled == enabled {
+		return plugin, nil
+	}
+	plugin.Enabled = enabled
+	if err := m.pluginStore.Update(ctx, plugin); err != nil {
+		return nil, fmt.Errorf("could not update plugin %s:%s: %w", name, version, err)
 	}
+	return plugin, nil
+}
 
+// Remove deletes a plugin version from the store entirely.
+func (m *PluginManager) Remove(ctx context.Context, name, version string) error {
+	if _, err := m.pluginStore.Find(ctx, name, version); err != nil {
+		return fmt.Errorf("could not find plugin %s:%s: %w", name, version, err)
+	}
+	if err := m.pluginStore.Delete(ctx, name, version); err != nil {
+		return fmt.Errorf("could not remove plugin %s:%s: %w", name, version, err)
+	}
 	return nil
 }
 
-// downloadZip is a helper function that downloads a zip from a URL and
-// writes it to a path in the local filesystem.
-func downloadZip(url, path string) error {
-	response, err := http.Get(url)
+// Inspect returns the full record for a single plugin version.
+func (m *PluginManager) Inspect(ctx context.Context, name, version string) (*types.Plugin, error) {
+	plugin, err := m.pluginStore.Find(ctx, name, version)
 	if err != nil {
-		return fmt.Errorf("could not get zip from url: %w", err)
+		return nil, fmt.Errorf("could not find plugin %s:%s: %w", name, version, err)
 	}
-	defer response.Body.Close()
+	return plugin, nil
+}
 
-	// Create the file on the local FS. If it exists, it will be truncated.
-	output, err := os.Create(path)
+// List returns the plugins matching the given filter.
+func (m *PluginManager) List(ctx context.Context, filter types.PluginFilter) ([]*types.Plugin, error) {
+	plugins, err := m.pluginStore.List(ctx, filter)
 	if err != nil {
-		return fmt.Errorf("could not create output file: %w", err)
+		return nil, fmt.Errorf("could not list plugins: %w", err)
 	}
-	defer output.Close()
+	return plugins, nil
+}
 
-	// Copy the zip output to the file.
-	_, err = io.Copy(output, response.Body)
-	if err != nil {
-		return fmt.Errorf("could not copy response body output to file: %w", err)
+// Populate fetches plugin definitions from every configured source (a local
+// or remote zip, an OCI registry, ...) and upserts them into the DB.
+func (m *PluginManager) Populate(ctx context.Context) error {
+	sources := m.config.CI.PluginSources
+	if len(sources) == 0 {
+		return fmt.Errorf("no plugin sources provided to read schemas from")
+	}
+
+	var pending []*pendingPlugin
+	for _, raw := range sources {
+		src, err := NewPluginSource(raw)
+		if err != nil {
+			return fmt.Errorf("could not create plugin source %q: %w", raw, err)
+		}
+		fetched, err := src.Fetch(ctx)
+		if err != nil {
+			return fmt.Errorf("could not fetch plugins from source %q: %w", raw, err)
+		}
+		pending = append(pending, fetched...)
+	}
+
+	// upsert any new plugins.
+	if err := m.upsertPending(ctx, pending); err != nil {
+		return fmt.Errorf("could not upsert plugins: %w", err)
 	}
 
 	return nil
 }
 
-// traverseAndUpsertPlugins traverses through the zip and upserts plugins into the database
-// if they are not present.
-func (m *PluginManager) traverseAndUpsertPlugins(ctx context.Context, rc *zip.ReadCloser) error {
-	plugins, err := m.pluginStore.ListAll(ctx)
+// upsertPending resolves the dependency graph declared across pending
+// plugins and upserts each one, in dependency order, into the DB.
+func (m *PluginManager) upsertPending(ctx context.Context, pending []*pendingPlugin) error {
+	existing, err := m.pluginStore.ListAll(ctx)
 	if err != nil {
 		return fmt.Errorf("could not list plugins: %w", err)
 	}
-	// Put the plugins in a map so we don't have to perform frequent DB queries.
-	pluginMap := map[string]*types.Plugin{}
-	for _, p := range plugins {
-		pluginMap[p.UID] = p
+
+	// pool tracks every version - already in the store, plus ones upserted
+	// earlier in this pass - that a requirement can be resolved against.
+	pool := map[string][]*types.Plugin{}
+	existingExact := map[string]*types.Plugin{}
+	for _, p := range existing {
+		pool[p.UID] = append(pool[p.UID], p)
+		existingExact[pluginKey(p.UID, p.Version)] = p
+	}
+
+	ordered, err := topoSortPending(pending)
+	if err != nil {
+		return fmt.Errorf("could not resolve plugin dependency graph: %w", err)
 	}
+
 	cnt := 0
-	for _, file := range rc.File {
-		matched, err := filepath.Match("**/plugins/*/*.yaml", file.Name)
-		if err != nil { // only returns BadPattern error which shouldn't happen
-			return fmt.Errorf("could not glob pattern: %w", err)
-		}
-		if !matched {
+	for _, pp := range ordered {
+		if err := validateRequirements(pp, pool); err != nil {
+			log.Warn().Str("name", pp.file).Err(err).Msg("could not satisfy plugin dependencies")
 			continue
 		}
-		fc, err := file.Open()
-		if err != nil {
-			log.Warn().Err(err).Str("name", file.Name).Msg("could not open file")
-			continue
-		}
-		defer fc.Close()
-		var buf bytes.Buffer
-		_, err = io.Copy(&buf, fc)
-		if err != nil {
-			log.Warn().Err(err).Str("name", file.Name).Msg("could not read file contents")
+		if err := m.checkSignature(pp); err != nil {
+			log.Warn().Str("name", pp.file).Err(err).Msg("refusing to upsert plugin")
 			continue
 		}
-		// schema should be a valid config - if not log an error and continue.
-		config, err := parse.ParseBytes(buf.Bytes())
-		if err != nil {
-			log.Warn().Err(err).Str("name", file.Name).Msg("could not parse schema into valid config")
-			continue
-		}
-
-		var desc string
-		switch vv := config.Spec.(type) {
-		case *v1yaml.PluginStep:
-			desc = vv.Description
-		case *v1yaml.PluginStage:
-			desc = vv.Description
-		default:
-			log.Warn().Str("name", file.Name).Msg("schema did not match a valid plugin schema")
-			continue
-		}
-
-		plugin := &types.Plugin{
-			Description: desc,
-			UID:         config.Name,
-			Type:        config.Type,
-			Spec:        buf.String(),
-		}
 
-		// Try to read the logo if it exists in the same directory
-		dir := filepath.Dir(file.Name)
-		logoFile := filepath.Join(dir, "logo.svg")
-		if lf, err := rc.Open(logoFile); err == nil { // if we can open the logo file
-			var lbuf bytes.Buffer
-			_, err = io.Copy(&lbuf, lf)
-			if err != nil {
-				log.Warn().Err(err).Str("name", file.Name).Msg("could not copy logo file")
-			} else {
-				plugin.Logo = lbuf.String()
+		if p, ok := existingExact[pluginKey(pp.plugin.UID, pp.plugin.Version)]; ok {
+			// If the source gave us a manifest digest and it's unchanged,
+			// we can short-circuit without comparing spec/logo content.
+			if pp.plugin.Digest != "" && pp.plugin.Digest == p.Digest {
+				continue
 			}
-		}
-
-		// If plugin already exists in the database, skip upsert
-		if p, ok := pluginMap[plugin.UID]; ok {
-			if p.Matches(plugin) {
+			if p.Matches(pp.plugin) {
 				continue
 			}
-
-		}
-
-		// If plugin name exists with a different spec, call update - otherwise call create.
-		// TODO: Once we start using versions, we can think of whether we want to
-		// keep different schemas for each version in the database. For now, we will
-		// simply overwrite the existing version with the new version.
-		if _, ok := pluginMap[plugin.UID]; ok {
-			err = m.pluginStore.Update(ctx, plugin)
-			if err != nil {
-				log.Warn().Str("name", file.Name).Err(err).Msg("could not update plugin")
+			// A content change must not clobber state the store alone owns:
+			// Enabled is only ever flipped via Enable/Disable, and Signature
+			// only changes when this pass actually verified a fresh one.
+			pp.plugin.ID = p.ID
+			pp.plugin.Enabled = p.Enabled
+			if pp.plugin.Signature == "" {
+				pp.plugin.Signature = p.Signature
+			}
+			if err := m.pluginStore.Update(ctx, pp.plugin); err != nil {
+				log.Warn().Str("name", pp.file).Err(err).Msg("could not update plugin")
 				continue
 			}
-			log.Info().Str("name", file.Name).Msg("detected changes: updated existing plugin entry")
+			log.Info().Str("name", pp.file).Msg("detected changes: updated existing plugin entry")
 		} else {
-			err = m.pluginStore.Create(ctx, plugin)
-			if err != nil {
-				log.Warn().Str("name", file.Name).Err(err).Msg("could not create plugin in DB")
+			if err := m.pluginStore.Create(ctx, pp.plugin); err != nil {
+				log.Warn().Str("name", pp.file).Err(err).Msg("could not create plugin in DB")
 				continue
 			}
 			cnt++
 		}
+		pool[pp.plugin.UID] = append(pool[pp.plugin.UID], pp.plugin)
 	}
 	log.Info().Msgf("added %d new entries to plugins", cnt)
 	return nil
-}
\ No newline at end of file
+}
+
+// pluginKey returns the (UID, Version) composite key used to detect whether
+// a parsed plugin already has a matching row in the store.
+func pluginKey(uid, version string) string {
+	return uid + "@" + version
+}
+
+// topoSortPending orders pending plugins so that a dependency which is also
+// being ingested in this pass is upserted before its dependents, and returns
+// an error if the declared requirements form a cycle.
+//
+// Identity for ordering purposes is (UID, Version), not UID alone: a single
+// batch can carry multiple versions of the same plugin (e.g. a channel sync
+// picking up both docker@1.0.0 and docker@1.1.0), and a requirement only
+// pins a UID plus a version range, not one specific pending entry. So every
+// pending version of a required UID is visited ahead of the dependent, and
+// the actual range match against those (plus already-stored) versions is
+// left to validateRequirements.
+func topoSortPending(pending []*pendingPlugin) ([]*pendingPlugin, error) {
+	byUID := map[string][]*pendingPlugin{}
+	for _, pp := range pending {
+		byUID[pp.plugin.UID] = append(byUID[pp.plugin.UID], pp)
+	}
+
+	const visiting, done = 1, 2
+	state := map[string]int{}
+	ordered := make([]*pendingPlugin, 0, len(pending))
+
+	var visit func(pp *pendingPlugin) error
+	visit = func(pp *pendingPlugin) error {
+		key := pluginKey(pp.plugin.UID, pp.plugin.Version)
+		switch state[key] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("circular plugin dependency detected at %q", key)
+		}
+		state[key] = visiting
+		for _, req := range pp.requires {
+			for _, dep := range byUID[req.Name] {
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		state[key] = done
+		ordered = append(ordered, pp)
+		return nil
+	}
+
+	for _, pp := range pending {
+		if err := visit(pp); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// validateRequirements refuses a plugin whose declared dependencies cannot
+// be satisfied by any resolvable version already available in pool.
+func validateRequirements(pp *pendingPlugin, pool map[string][]*types.Plugin) error {
+	for _, req := range pp.requires {
+		if _, err := highestMatching(pool[req.Name], req.Version); err != nil {
+			return fmt.Errorf("requirement %s %s not satisfied: %w", req.Name, req.Version, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/harness/gitness/types"
+)
+
+// fakePluginStore is an in-memory store.PluginStore, keyed by (UID,
+// Version), used to exercise upsertPending without a real database.
+type fakePluginStore struct {
+	plugins map[string]*types.Plugin
+}
+
+func newFakePluginStore(existing ...*types.Plugin) *fakePluginStore {
+	s := &fakePluginStore{plugins: map[string]*types.Plugin{}}
+	for _, p := range existing {
+		s.plugins[pluginKey(p.UID, p.Version)] = p
+	}
+	return s
+}
+
+func (s *fakePluginStore) Find(_ context.Context, uid, version string) (*types.Plugin, error) {
+	p, ok := s.plugins[pluginKey(uid, version)]
+	if !ok {
+		return nil, fmt.Errorf("plugin %s not found", pluginKey(uid, version))
+	}
+	return p, nil
+}
+
+func (s *fakePluginStore) List(_ context.Context, _ types.PluginFilter) ([]*types.Plugin, error) {
+	return s.ListAll(context.Background())
+}
+
+func (s *fakePluginStore) ListAll(_ context.Context) ([]*types.Plugin, error) {
+	out := make([]*types.Plugin, 0, len(s.plugins))
+	for _, p := range s.plugins {
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (s *fakePluginStore) ListVersions(_ context.Context, uid string) ([]*types.Plugin, error) {
+	var out []*types.Plugin
+	for _, p := range s.plugins {
+		if p.UID == uid {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (s *fakePluginStore) Create(_ context.Context, p *types.Plugin) error {
+	s.plugins[pluginKey(p.UID, p.Version)] = p
+	return nil
+}
+
+func (s *fakePluginStore) Update(_ context.Context, p *types.Plugin) error {
+	s.plugins[pluginKey(p.UID, p.Version)] = p
+	return nil
+}
+
+func (s *fakePluginStore) Delete(_ context.Context, uid, version string) error {
+	delete(s.plugins, pluginKey(uid, version))
+	return nil
+}
+
+func TestHighestMatching(t *testing.T) {
+	candidates := []*types.Plugin{
+		{UID: "docker", Version: "1.0.0", Enabled: true},
+		{UID: "docker", Version: "1.2.0", Enabled: true},
+		{UID: "docker", Version: "1.5.0", Enabled: false},
+		{UID: "docker", Version: "2.0.0", Enabled: true},
+		{UID: "docker", Version: "not-semver", Enabled: true},
+	}
+
+	got, err := highestMatching(candidates, "^1.0.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Version != "1.2.0" {
+		t.Fatalf("expected highest matching enabled version 1.2.0, got %s", got.Version)
+	}
+
+	if _, err := highestMatching(candidates, ">=3.0.0"); err == nil {
+		t.Fatalf("expected error when no candidate satisfies the range")
+	}
+
+	if _, err := highestMatching(candidates, "not a range"); err == nil {
+		t.Fatalf("expected error for an invalid range")
+	}
+}
+
+func TestHighestMatchingIgnoresDisabledAndInvalidSemver(t *testing.T) {
+	candidates := []*types.Plugin{
+		{UID: "docker", Version: "1.9.0", Enabled: false},
+		{UID: "docker", Version: "not-semver", Enabled: true},
+		{UID: "docker", Version: "1.0.0", Enabled: true},
+	}
+
+	got, err := highestMatching(candidates, "*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Version != "1.0.0" {
+		t.Fatalf("expected disabled 1.9.0 and invalid not-semver to be skipped, got %s", got.Version)
+	}
+}
+
+func TestTopoSortPendingOrdersDependencyBeforeDependent(t *testing.T) {
+	base := &pendingPlugin{plugin: &types.Plugin{UID: "base", Version: "1.0.0"}}
+	app := &pendingPlugin{
+		plugin:   &types.Plugin{UID: "app", Version: "1.0.0"},
+		requires: []pluginRequirement{{Name: "base", Version: "^1.0.0"}},
+	}
+
+	ordered, err := topoSortPending([]*pendingPlugin{app, base})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ordered) != 2 || ordered[0].plugin.UID != "base" || ordered[1].plugin.UID != "app" {
+		t.Fatalf("expected [base app], got %v", pendingUIDs(ordered))
+	}
+}
+
+// TestTopoSortPendingKeepsBothVersionsOfSameUID is a regression test: a batch
+// containing two versions of the same plugin UID (e.g. a channel sync that
+// picks up docker@1.0.0 and docker@1.1.0 together) must not have one version
+// silently dropped because the other was already marked done.
+func TestTopoSortPendingKeepsBothVersionsOfSameUID(t *testing.T) {
+	dockerOld := &pendingPlugin{plugin: &types.Plugin{UID: "docker", Version: "1.0.0"}}
+	dockerNew := &pendingPlugin{plugin: &types.Plugin{UID: "docker", Version: "1.1.0"}}
+	app := &pendingPlugin{
+		plugin:   &types.Plugin{UID: "app", Version: "1.0.0"},
+		requires: []pluginRequirement{{Name: "docker", Version: "^1.0.0"}},
+	}
+
+	ordered, err := topoSortPending([]*pendingPlugin{dockerOld, dockerNew, app})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ordered) != 3 {
+		t.Fatalf("expected all 3 pending entries to survive, got %d: %v", len(ordered), pendingUIDs(ordered))
+	}
+
+	appIdx, oldIdx, newIdx := -1, -1, -1
+	for i, pp := range ordered {
+		switch {
+		case pp == app:
+			appIdx = i
+		case pp == dockerOld:
+			oldIdx = i
+		case pp == dockerNew:
+			newIdx = i
+		}
+	}
+	if oldIdx > appIdx || newIdx > appIdx {
+		t.Fatalf("expected both docker versions ordered before app, got %v", pendingUIDs(ordered))
+	}
+}
+
+func TestTopoSortPendingDetectsCycle(t *testing.T) {
+	a := &pendingPlugin{
+		plugin:   &types.Plugin{UID: "a", Version: "1.0.0"},
+		requires: []pluginRequirement{{Name: "b", Version: "*"}},
+	}
+	b := &pendingPlugin{
+		plugin:   &types.Plugin{UID: "b", Version: "1.0.0"},
+		requires: []pluginRequirement{{Name: "a", Version: "*"}},
+	}
+
+	if _, err := topoSortPending([]*pendingPlugin{a, b}); err == nil {
+		t.Fatalf("expected circular dependency error")
+	}
+}
+
+// TestUpsertPendingKeepsDisabledOnContentChange is a regression test: a
+// content change to an existing plugin version must not silently re-enable
+// it, or drop a previously verified signature that this pass didn't
+// re-verify - Update only ever intends to persist the new content.
+func TestUpsertPendingKeepsDisabledOnContentChange(t *testing.T) {
+	existing := &types.Plugin{
+		UID:       "docker",
+		Version:   "1.0.0",
+		Spec:      "old spec",
+		Enabled:   false,
+		Signature: "existing-signature",
+	}
+	store := newFakePluginStore(existing)
+	m := &PluginManager{config: &types.Config{}, pluginStore: store}
+
+	pending := []*pendingPlugin{
+		{plugin: &types.Plugin{UID: "docker", Version: "1.0.0", Spec: "new spec", Enabled: true}, file: "docker.yml"},
+	}
+
+	if err := m.upsertPending(context.Background(), pending); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := store.Find(context.Background(), "docker", "1.0.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Spec != "new spec" {
+		t.Fatalf("expected the new spec to be persisted, got %q", got.Spec)
+	}
+	if got.Enabled {
+		t.Fatalf("expected a disabled plugin to stay disabled across a content update")
+	}
+	if got.Signature != "existing-signature" {
+		t.Fatalf("expected the existing signature to be kept when this pass verified none, got %q", got.Signature)
+	}
+}
+
+func pendingUIDs(pending []*pendingPlugin) []string {
+	uids := make([]string, len(pending))
+	for i, pp := range pending {
+		uids[i] = pluginKey(pp.plugin.UID, pp.plugin.Version)
+	}
+	return uids
+}
@@ -0,0 +1,200 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// translateRange rewrites an npm-style semver range - the syntax documented
+// for a plugin's `requires:` entry and for pipeline step version pins, e.g.
+// "^1.2", "~1.2.3", ">=2.0.0 <3", "*" - into the comparator-only syntax
+// blang/semver's ParseRange actually accepts. blang has no caret/tilde
+// support and requires every bound to be a fully-qualified X.Y.Z version, so
+// without this translation every documented range shape fails to parse.
+func translateRange(rng string) (string, error) {
+	rng = strings.TrimSpace(rng)
+	if rng == "" || rng == "*" {
+		return ">=0.0.0", nil
+	}
+
+	orGroups := strings.Split(rng, "||")
+	translated := make([]string, 0, len(orGroups))
+	for _, group := range orGroups {
+		clauses := strings.Fields(group)
+		if len(clauses) == 0 {
+			return "", fmt.Errorf("empty clause in range %q", rng)
+		}
+		parts := make([]string, 0, len(clauses))
+		for _, clause := range clauses {
+			part, err := translateClause(clause)
+			if err != nil {
+				return "", fmt.Errorf("range %q: %w", rng, err)
+			}
+			parts = append(parts, part)
+		}
+		translated = append(translated, strings.Join(parts, " "))
+	}
+	return strings.Join(translated, " || "), nil
+}
+
+// translateClause translates a single space-delimited comparator from an
+// npm-style range into one or more blang-style comparators.
+func translateClause(clause string) (string, error) {
+	op, ver := splitRangeOp(clause)
+	switch op {
+	case "^":
+		return expandCaret(ver)
+	case "~":
+		return expandTilde(ver)
+	case ">", ">=", "<", "<=", "!=", "!":
+		padded, err := padVersion(ver)
+		if err != nil {
+			return "", err
+		}
+		return op + padded, nil
+	case "", "=", "==":
+		comps, count, err := splitVersion(ver)
+		if err != nil {
+			return "", err
+		}
+		if count == 3 {
+			return "=" + versionString(comps), nil
+		}
+		// A bare partial version (e.g. "1.2", "1", "1.x") matches anything
+		// in that range, not just its zero-filled exact value.
+		return expandPartial(comps, count)
+	default:
+		return "", fmt.Errorf("unsupported operator %q in clause %q", op, clause)
+	}
+}
+
+// splitRangeOp splits the leading comparator operator, if any, off a clause.
+// Longer operators are checked first so ">=" isn't mistaken for ">", etc.
+func splitRangeOp(clause string) (op, version string) {
+	for _, candidate := range []string{"^", "~", ">=", "<=", ">", "<", "!=", "!", "==", "="} {
+		if strings.HasPrefix(clause, candidate) {
+			return candidate, strings.TrimPrefix(clause, candidate)
+		}
+	}
+	return "", clause
+}
+
+// splitVersion parses the leading numeric components of a (possibly
+// partial or wildcarded, e.g. "1", "1.2", "1.x") version string, stopping at
+// the first "x"/"*" component or the end of the string.
+func splitVersion(ver string) (comps []int, count int, err error) {
+	for _, seg := range strings.Split(ver, ".") {
+		if seg == "x" || seg == "X" || seg == "*" {
+			break
+		}
+		n, err := strconv.Atoi(seg)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid version %q: %w", ver, err)
+		}
+		comps = append(comps, n)
+	}
+	if len(comps) == 0 {
+		return nil, 0, fmt.Errorf("invalid version %q", ver)
+	}
+	return comps, len(comps), nil
+}
+
+// padVersion zero-fills a partial or wildcarded version out to X.Y.Z, e.g.
+// "<3" becomes "<3.0.0" and "<1.x" becomes "<1.0.0".
+func padVersion(ver string) (string, error) {
+	comps, _, err := splitVersion(ver)
+	if err != nil {
+		return "", err
+	}
+	return versionString(comps), nil
+}
+
+func versionString(comps []int) string {
+	for len(comps) < 3 {
+		comps = append(comps, 0)
+	}
+	return fmt.Sprintf("%d.%d.%d", comps[0], comps[1], comps[2])
+}
+
+// expandPartial expands a bare partial version (no operator, fewer than 3
+// components, e.g. "1" or "1.2") into the range of versions it's meant to
+// match: "1" matches any 1.x.x, "1.2" matches any 1.2.x.
+func expandPartial(comps []int, count int) (string, error) {
+	major := comps[0]
+	switch count {
+	case 1:
+		return fmt.Sprintf(">=%d.0.0 <%d.0.0", major, major+1), nil
+	case 2:
+		minor := comps[1]
+		return fmt.Sprintf(">=%d.%d.0 <%d.%d.0", major, minor, major, minor+1), nil
+	default:
+		return "", fmt.Errorf("invalid partial version %v", comps)
+	}
+}
+
+// expandCaret translates a "^" range, which allows changes that don't
+// modify the left-most non-zero component - the usual "compatible with"
+// range for a semver dependency.
+func expandCaret(ver string) (string, error) {
+	comps, count, err := splitVersion(ver)
+	if err != nil {
+		return "", err
+	}
+	major := comps[0]
+	minor, patch := 0, 0
+	if count >= 2 {
+		minor = comps[1]
+	}
+	if count >= 3 {
+		patch = comps[2]
+	}
+
+	var upperMajor, upperMinor, upperPatch int
+	switch {
+	case major != 0:
+		upperMajor = major + 1
+	case count >= 2 && minor != 0:
+		upperMinor = minor + 1
+	case count == 3:
+		upperPatch = patch + 1
+	case count == 2: // ^0.0
+		upperMinor = 1
+	default: // count == 1, i.e. "^0"
+		upperMajor = 1
+	}
+
+	lower := fmt.Sprintf("%d.%d.%d", major, minor, patch)
+	upper := fmt.Sprintf("%d.%d.%d", upperMajor, upperMinor, upperPatch)
+	return fmt.Sprintf(">=%s <%s", lower, upper), nil
+}
+
+// expandTilde translates a "~" range, which allows patch-level changes if a
+// minor version is given, or minor-level changes if not.
+func expandTilde(ver string) (string, error) {
+	comps, count, err := splitVersion(ver)
+	if err != nil {
+		return "", err
+	}
+	major := comps[0]
+	minor, patch := 0, 0
+	if count >= 2 {
+		minor = comps[1]
+	}
+	if count >= 3 {
+		patch = comps[2]
+	}
+
+	lower := fmt.Sprintf("%d.%d.%d", major, minor, patch)
+	var upper string
+	if count >= 2 {
+		upper = fmt.Sprintf("%d.%d.0", major, minor+1)
+	} else {
+		upper = fmt.Sprintf("%d.0.0", major+1)
+	}
+	return fmt.Sprintf(">=%s <%s", lower, upper), nil
+}
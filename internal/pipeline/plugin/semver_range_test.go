@@ -0,0 +1,59 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/blang/semver/v4"
+)
+
+// TestTranslateRangeMatchesExpectedVersions is a regression test for the
+// npm-style range syntax this package documents (GetLookupFn, plugin
+// `requires:` entries): blang/semver has no caret/tilde support and requires
+// fully-qualified X.Y.Z bounds, so every one of these needs translation
+// before ParseRange will even accept it.
+func TestTranslateRangeMatchesExpectedVersions(t *testing.T) {
+	tests := []struct {
+		rng     string
+		matches []string
+		misses  []string
+	}{
+		{rng: "^1.2", matches: []string{"1.2.0", "1.9.9"}, misses: []string{"1.0.0", "2.0.0"}},
+		{rng: "^1.2.0", matches: []string{"1.2.0", "1.2.3"}, misses: []string{"1.1.9", "2.0.0"}},
+		{rng: ">=2.0.0 <3", matches: []string{"2.0.0", "2.9.9"}, misses: []string{"1.9.9", "3.0.0"}},
+		{rng: "*", matches: []string{"0.0.1", "1.0.0", "9.9.9"}},
+		{rng: "~1.2.3", matches: []string{"1.2.3", "1.2.9"}, misses: []string{"1.2.2", "1.3.0"}},
+		{rng: "^0.2.3", matches: []string{"0.2.3", "0.2.9"}, misses: []string{"0.2.2", "0.3.0"}},
+		{rng: "^0.0.3", matches: []string{"0.0.3"}, misses: []string{"0.0.4", "0.0.2"}},
+	}
+
+	for _, tt := range tests {
+		translated, err := translateRange(tt.rng)
+		if err != nil {
+			t.Fatalf("range %q: unexpected translate error: %v", tt.rng, err)
+		}
+		r, err := semver.ParseRange(translated)
+		if err != nil {
+			t.Fatalf("range %q translated to %q, which blang/semver rejected: %v", tt.rng, translated, err)
+		}
+		for _, v := range tt.matches {
+			if !r(semver.MustParse(v)) {
+				t.Errorf("range %q (translated %q): expected %s to match", tt.rng, translated, v)
+			}
+		}
+		for _, v := range tt.misses {
+			if r(semver.MustParse(v)) {
+				t.Errorf("range %q (translated %q): expected %s not to match", tt.rng, translated, v)
+			}
+		}
+	}
+}
+
+func TestTranslateRangeInvalidVersionErrors(t *testing.T) {
+	if _, err := translateRange("^not-a-version"); err == nil {
+		t.Fatalf("expected an error for an invalid version in a caret range")
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// checkSignature decides whether a pending plugin is allowed to be upserted
+// given the manager's signing configuration. It returns an error - safe to
+// log and skip the plugin on - when the plugin must be refused; on success
+// it stamps the verified signature onto pp.plugin.
+func (m *PluginManager) checkSignature(pp *pendingPlugin) error {
+	keyringPath := m.config.CI.PluginSigningKeyPath
+	if keyringPath == "" {
+		if m.config.CI.RequireSignedPlugins {
+			return fmt.Errorf("no plugin signing keyring configured but signed plugins are required")
+		}
+		return nil
+	}
+	if len(pp.signature) == 0 {
+		if m.config.CI.RequireSignedPlugins {
+			return fmt.Errorf("plugin is unsigned")
+		}
+		return nil
+	}
+
+	checksum, err := hexDecodeChecksum(pp.plugin.Checksum)
+	if err != nil {
+		return fmt.Errorf("could not decode plugin checksum: %w", err)
+	}
+
+	// A signature that's present but doesn't verify is always refused,
+	// regardless of RequireSignedPlugins - that flag only controls whether
+	// an *unsigned* plugin is tolerated, not whether a tampered one is.
+	if err := verifyDetachedSignature(keyringPath, checksum, pp.signature); err != nil {
+		return fmt.Errorf("signature verification failed: %w", err)
+	}
+
+	pp.plugin.Signature = base64.StdEncoding.EncodeToString(pp.signature)
+	return nil
+}
+
+// hexDecodeChecksum decodes the hex-encoded checksum stored on a plugin back
+// into raw bytes for signature verification.
+func hexDecodeChecksum(checksum string) ([]byte, error) {
+	return hex.DecodeString(checksum)
+}
+
+// verifyDetachedSignature checks a detached PGP signature for content
+// against the armored public keyring found at keyringPath.
+func verifyDetachedSignature(keyringPath string, content, signature []byte) error {
+	keyringFile, err := os.Open(keyringPath)
+	if err != nil {
+		return fmt.Errorf("could not open plugin signing keyring: %w", err)
+	}
+	defer keyringFile.Close()
+
+	keyring, err := openpgp.ReadArmoredKeyRing(keyringFile)
+	if err != nil {
+		return fmt.Errorf("could not read plugin signing keyring: %w", err)
+	}
+
+	_, err = openpgp.CheckDetachedSignature(keyring, bytes.NewReader(content), bytes.NewReader(signature), nil)
+	if err != nil {
+		return fmt.Errorf("could not verify signature: %w", err)
+	}
+	return nil
+}
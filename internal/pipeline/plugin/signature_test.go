@@ -0,0 +1,136 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/harness/gitness/types"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+// newTestKeyring generates a fresh PGP entity, writes its armored public key
+// to a keyring file under dir, and returns the keyring path alongside the
+// entity so tests can produce detached signatures with it.
+func newTestKeyring(t *testing.T, dir string) (string, *openpgp.Entity) {
+	t.Helper()
+	entity, err := openpgp.NewEntity("test", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatalf("could not generate test key: %v", err)
+	}
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("could not open armor encoder: %v", err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatalf("could not serialize public key: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("could not close armor encoder: %v", err)
+	}
+
+	path := filepath.Join(dir, "keyring.asc")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("could not write keyring: %v", err)
+	}
+	return path, entity
+}
+
+func sign(t *testing.T, entity *openpgp.Entity, content []byte) []byte {
+	t.Helper()
+	var sig bytes.Buffer
+	if err := openpgp.DetachSign(&sig, entity, bytes.NewReader(content), nil); err != nil {
+		t.Fatalf("could not sign content: %v", err)
+	}
+	return sig.Bytes()
+}
+
+func newPendingWithChecksum(checksum []byte) *pendingPlugin {
+	return &pendingPlugin{
+		plugin: &types.Plugin{UID: "docker", Version: "1.0.0", Checksum: hex.EncodeToString(checksum)},
+		file:   "docker.yml",
+	}
+}
+
+func TestCheckSignatureNoKeyringConfigured(t *testing.T) {
+	m := &PluginManager{config: &types.Config{}}
+	pp := newPendingWithChecksum([]byte("content"))
+
+	if err := m.checkSignature(pp); err != nil {
+		t.Fatalf("expected no keyring + not required to be allowed, got: %v", err)
+	}
+
+	m.config.CI.RequireSignedPlugins = true
+	if err := m.checkSignature(pp); err == nil {
+		t.Fatalf("expected error when signed plugins are required but no keyring is configured")
+	}
+}
+
+func TestCheckSignatureUnsigned(t *testing.T) {
+	dir := t.TempDir()
+	keyringPath, _ := newTestKeyring(t, dir)
+
+	m := &PluginManager{config: &types.Config{}}
+	m.config.CI.PluginSigningKeyPath = keyringPath
+	pp := newPendingWithChecksum([]byte("content"))
+
+	if err := m.checkSignature(pp); err != nil {
+		t.Fatalf("expected unsigned plugin to be allowed when signing isn't required, got: %v", err)
+	}
+
+	m.config.CI.RequireSignedPlugins = true
+	if err := m.checkSignature(pp); err == nil {
+		t.Fatalf("expected error for an unsigned plugin when signing is required")
+	}
+}
+
+func TestCheckSignatureValid(t *testing.T) {
+	dir := t.TempDir()
+	keyringPath, entity := newTestKeyring(t, dir)
+	content := []byte("content")
+
+	m := &PluginManager{config: &types.Config{}}
+	m.config.CI.PluginSigningKeyPath = keyringPath
+	pp := newPendingWithChecksum(content)
+	pp.signature = sign(t, entity, content)
+
+	if err := m.checkSignature(pp); err != nil {
+		t.Fatalf("expected valid signature to verify, got: %v", err)
+	}
+	if pp.plugin.Signature == "" {
+		t.Fatalf("expected verified signature to be stamped onto the plugin")
+	}
+}
+
+// TestCheckSignatureInvalidIsAlwaysRefused is a regression test: a present
+// but invalid/tampered signature must be refused even when
+// RequireSignedPlugins is false - that flag only governs whether an
+// *unsigned* plugin is tolerated, not whether a tampered one is.
+func TestCheckSignatureInvalidIsAlwaysRefused(t *testing.T) {
+	dir := t.TempDir()
+	keyringPath, entity := newTestKeyring(t, dir)
+
+	m := &PluginManager{config: &types.Config{}}
+	m.config.CI.PluginSigningKeyPath = keyringPath
+	pp := newPendingWithChecksum([]byte("content"))
+	pp.signature = sign(t, entity, []byte("different content"))
+
+	if err := m.checkSignature(pp); err == nil {
+		t.Fatalf("expected an invalid signature to be refused even when signing isn't required")
+	}
+
+	m.config.CI.RequireSignedPlugins = true
+	if err := m.checkSignature(pp); err == nil {
+		t.Fatalf("expected an invalid signature to be refused when signing is required")
+	}
+}
@@ -0,0 +1,359 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package plugin
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/harness/gitness/types"
+
+	"github.com/blang/semver/v4"
+	v1yaml "github.com/drone/spec/dist/go"
+	"github.com/drone/spec/dist/go/parse"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+const (
+	// mediaTypePluginSpec is the OCI layer media type carrying the plugin
+	// YAML spec.
+	mediaTypePluginSpec = "application/vnd.gitness.plugin.spec.v1+yaml"
+	// mediaTypePluginLogo is the OCI layer media type carrying an optional
+	// logo for the plugin.
+	mediaTypePluginLogo = "image/svg+xml"
+	// mediaTypePluginSignature is the OCI layer media type carrying a
+	// detached signature over the plugin's checksum, i.e. the raw sha256
+	// digest of its spec and privileges.
+	mediaTypePluginSignature = "application/vnd.gitness.plugin.signature.v1"
+)
+
+// PluginSource knows how to fetch plugin definitions from a single
+// configured location and parse them into pendingPlugin candidates that are
+// ready for dependency validation and upsert.
+type PluginSource interface {
+	Fetch(ctx context.Context) ([]*pendingPlugin, error)
+}
+
+// NewPluginSource builds a PluginSource from a configured URI, dispatching
+// on its scheme: zip:// for a local archive, http(s):// for a remote zip,
+// and oci:// for an OCI registry reference.
+func NewPluginSource(raw string) (PluginSource, error) {
+	switch {
+	case strings.HasPrefix(raw, "zip://"):
+		return &zipSource{path: strings.TrimPrefix(raw, "zip://")}, nil
+	case strings.HasPrefix(raw, "http://"), strings.HasPrefix(raw, "https://"):
+		return &httpZipSource{url: raw}, nil
+	case strings.HasPrefix(raw, "oci://"):
+		return &ociSource{ref: strings.TrimPrefix(raw, "oci://")}, nil
+	default:
+		return nil, fmt.Errorf("unsupported plugin source %q: expected a zip://, http(s):// or oci:// URI", raw)
+	}
+}
+
+// zipSource reads plugin specs out of a zip archive already present on the
+// local filesystem.
+type zipSource struct {
+	path string
+}
+
+func (s *zipSource) Fetch(_ context.Context) ([]*pendingPlugin, error) {
+	rc, err := zip.OpenReader(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open zip for reading: %w", err)
+	}
+	defer rc.Close()
+	return parseZipPlugins(&rc.Reader)
+}
+
+// httpZipSource downloads a zip archive from a remote URL before reading
+// plugin specs out of it the same way zipSource does.
+type httpZipSource struct {
+	url string
+}
+
+func (s *httpZipSource) Fetch(_ context.Context) ([]*pendingPlugin, error) {
+	f, err := os.CreateTemp(os.TempDir(), "plugins.zip")
+	if err != nil {
+		return nil, fmt.Errorf("could not create temp file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if err := downloadZip(s.url, f.Name()); err != nil {
+		return nil, fmt.Errorf("could not download remote zip: %w", err)
+	}
+
+	rc, err := zip.OpenReader(f.Name())
+	if err != nil {
+		return nil, fmt.Errorf("could not open zip for reading: %w", err)
+	}
+	defer rc.Close()
+	return parseZipPlugins(&rc.Reader)
+}
+
+// downloadZip is a helper function that downloads a zip from a URL and
+// writes it to a path in the local filesystem.
+func downloadZip(url, path string) error {
+	response, err := http.Get(url)
+	if err != nil {
+		return fmt.Errorf("could not get zip from url: %w", err)
+	}
+	defer response.Body.Close()
+
+	// Create the file on the local FS. If it exists, it will be truncated.
+	output, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create output file: %w", err)
+	}
+	defer output.Close()
+
+	// Copy the zip output to the file.
+	_, err = io.Copy(output, response.Body)
+	if err != nil {
+		return fmt.Errorf("could not copy response body output to file: %w", err)
+	}
+
+	return nil
+}
+
+// parseZipPlugins walks every `**/plugins/*/*.yaml` entry in the archive and
+// builds a pendingPlugin for each one, logging and skipping anything that
+// doesn't look like a valid plugin definition rather than failing the batch.
+func parseZipPlugins(rc *zip.Reader) ([]*pendingPlugin, error) {
+	var out []*pendingPlugin
+	for _, file := range rc.File {
+		matched, err := filepath.Match("**/plugins/*/*.yaml", file.Name)
+		if err != nil { // only returns BadPattern error which shouldn't happen
+			return nil, fmt.Errorf("could not glob pattern: %w", err)
+		}
+		if !matched {
+			continue
+		}
+		fc, err := file.Open()
+		if err != nil {
+			log.Warn().Err(err).Str("name", file.Name).Msg("could not open file")
+			continue
+		}
+		var buf bytes.Buffer
+		_, err = io.Copy(&buf, fc)
+		fc.Close()
+		if err != nil {
+			log.Warn().Err(err).Str("name", file.Name).Msg("could not read file contents")
+			continue
+		}
+
+		pp, err := buildPendingPlugin(buf.Bytes(), file.Name)
+		if err != nil {
+			log.Warn().Err(err).Str("name", file.Name).Msg("could not parse schema into valid plugin")
+			continue
+		}
+
+		// Try to read the logo if it exists in the same directory.
+		dir := filepath.Dir(file.Name)
+		logoFile := filepath.Join(dir, "logo.svg")
+		if lf, err := rc.Open(logoFile); err == nil { // if we can open the logo file
+			var lbuf bytes.Buffer
+			_, err = io.Copy(&lbuf, lf)
+			lf.Close()
+			if err != nil {
+				log.Warn().Err(err).Str("name", file.Name).Msg("could not copy logo file")
+			} else {
+				pp.plugin.Logo = lbuf.String()
+			}
+		}
+
+		// Try to read a detached signature alongside the spec, e.g.
+		// repo/plugins/docker/docker.yaml.sig next to repo/plugins/docker/docker.yaml.
+		sigFile := file.Name + ".sig"
+		if sf, err := rc.Open(sigFile); err == nil {
+			var sbuf bytes.Buffer
+			_, err = io.Copy(&sbuf, sf)
+			sf.Close()
+			if err != nil {
+				log.Warn().Err(err).Str("name", file.Name).Msg("could not copy plugin signature")
+			} else {
+				pp.signature = sbuf.Bytes()
+			}
+		}
+
+		out = append(out, pp)
+	}
+	return out, nil
+}
+
+// ociSource pulls a single plugin version from an OCI-compatible registry.
+// The plugin is published as a manifest whose layers carry the plugin YAML
+// spec and, optionally, a logo.
+type ociSource struct {
+	ref string // registry/repo:tag
+}
+
+func (s *ociSource) Fetch(ctx context.Context) ([]*pendingPlugin, error) {
+	repo, err := remote.NewRepository(s.ref)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve OCI repository %q: %w", s.ref, err)
+	}
+
+	// Split registry/repository from the tag or digest properly instead of
+	// hand-rolling it: a digest-pinned ref (repo@sha256:<hex>) has a ':'
+	// inside the digest itself, which sits after the last '/' just like a
+	// tag's ':' would, so a naive LastIndex(":") split mistakes the digest's
+	// hex for a tag name.
+	ref, err := registry.ParseReference(s.ref)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse OCI reference %q: %w", s.ref, err)
+	}
+
+	// FetchReference resolves the tag or digest to a manifest descriptor and
+	// returns a reader that verifies its content against that descriptor's
+	// digest.
+	manifestDesc, manifestRC, err := repo.FetchReference(ctx, ref.ReferenceOrDefault())
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch manifest for %q: %w", s.ref, err)
+	}
+	defer manifestRC.Close()
+
+	manifestBytes, err := content.ReadAll(manifestRC, manifestDesc)
+	if err != nil {
+		return nil, fmt.Errorf("could not read manifest for %q: %w", s.ref, err)
+	}
+
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return nil, fmt.Errorf("could not decode manifest for %q: %w", s.ref, err)
+	}
+
+	var specBytes, logoBytes, sigBytes []byte
+	for _, layer := range manifest.Layers {
+		layerRC, err := repo.Fetch(ctx, layer)
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch layer %s: %w", layer.Digest, err)
+		}
+		b, err := content.ReadAll(layerRC, layer)
+		layerRC.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not read layer %s: %w", layer.Digest, err)
+		}
+		switch layer.MediaType {
+		case mediaTypePluginSpec:
+			specBytes = b
+		case mediaTypePluginLogo:
+			logoBytes = b
+		case mediaTypePluginSignature:
+			sigBytes = b
+		}
+	}
+	if specBytes == nil {
+		return nil, fmt.Errorf("manifest %s for %q did not contain a %s layer",
+			manifestDesc.Digest, s.ref, mediaTypePluginSpec)
+	}
+
+	pp, err := buildPendingPlugin(specBytes, s.ref)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse plugin spec from %q: %w", s.ref, err)
+	}
+	pp.plugin.Digest = manifestDesc.Digest.String()
+	if logoBytes != nil {
+		pp.plugin.Logo = string(logoBytes)
+	}
+	pp.signature = sigBytes
+	return []*pendingPlugin{pp}, nil
+}
+
+// pluginRequirement describes a dependency a plugin declares on another
+// plugin via a `requires:` entry in its YAML spec. This is a gitness
+// extension that the upstream drone/spec parser doesn't know about, so it's
+// read from the raw YAML separately from the rest of the config.
+type pluginRequirement struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+}
+
+type pluginManifest struct {
+	Requires   []pluginRequirement    `yaml:"requires"`
+	Privileges types.PluginPrivileges `yaml:"privileges"`
+}
+
+// pendingPlugin is a plugin parsed out of a source that is waiting to have
+// its dependencies and signature validated before it's upserted. file
+// identifies where it came from, for logging (a zip entry path, or an OCI
+// ref). signature is the raw detached signature bytes found alongside the
+// spec, if any.
+type pendingPlugin struct {
+	plugin    *types.Plugin
+	requires  []pluginRequirement
+	signature []byte
+	file      string
+}
+
+// buildPendingPlugin parses a single plugin YAML document - shared by every
+// plugin source - into a pendingPlugin ready for dependency and signature
+// validation.
+func buildPendingPlugin(raw []byte, label string) (*pendingPlugin, error) {
+	config, err := parse.ParseBytes(raw)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse schema into valid config: %w", err)
+	}
+
+	var desc string
+	switch vv := config.Spec.(type) {
+	case *v1yaml.PluginStep:
+		desc = vv.Description
+	case *v1yaml.PluginStage:
+		desc = vv.Description
+	default:
+		return nil, fmt.Errorf("schema did not match a valid plugin schema")
+	}
+
+	if _, err := semver.Parse(config.Version); err != nil {
+		return nil, fmt.Errorf("plugin version %q is not valid semver: %w", config.Version, err)
+	}
+
+	var manifest pluginManifest
+	if err := yaml.Unmarshal(raw, &manifest); err != nil {
+		return nil, fmt.Errorf("could not parse plugin requirements: %w", err)
+	}
+
+	privileges, err := json.Marshal(manifest.Privileges)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode plugin privileges: %w", err)
+	}
+
+	plugin := &types.Plugin{
+		Description: desc,
+		UID:         config.Name,
+		Version:     config.Version,
+		Type:        config.Type,
+		Spec:        string(raw),
+		Privileges:  string(privileges),
+		Checksum:    pluginChecksum(raw, privileges),
+		Enabled:     true,
+	}
+
+	return &pendingPlugin{plugin: plugin, requires: manifest.Requires, file: label}, nil
+}
+
+// pluginChecksum computes the stable hex sha256 digest a plugin signature is
+// expected to cover: the raw spec followed by its canonical JSON privileges.
+func pluginChecksum(spec, privileges []byte) string {
+	h := sha256.New()
+	h.Write(spec)
+	h.Write(privileges)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,36 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package store
+
+import (
+	"context"
+
+	"github.com/harness/gitness/types"
+)
+
+// PluginStore defines the plugin data storage.
+type PluginStore interface {
+	// Find returns a plugin given a uid and exact version.
+	Find(ctx context.Context, uid, version string) (*types.Plugin, error)
+
+	// List returns back the list of plugins matching the given filter.
+	List(ctx context.Context, filter types.PluginFilter) ([]*types.Plugin, error)
+
+	// ListAll returns the full list of plugins in the database.
+	ListAll(ctx context.Context) ([]*types.Plugin, error)
+
+	// ListVersions returns every version stored for a given plugin uid, used
+	// to resolve semver ranges against the set of available versions.
+	ListVersions(ctx context.Context, uid string) ([]*types.Plugin, error)
+
+	// Create creates a new plugin.
+	Create(ctx context.Context, plugin *types.Plugin) error
+
+	// Update updates an existing plugin.
+	Update(ctx context.Context, plugin *types.Plugin) error
+
+	// Delete deletes a plugin given a uid and version.
+	Delete(ctx context.Context, uid, version string) error
+}
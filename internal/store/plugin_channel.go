@@ -0,0 +1,24 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package store
+
+import (
+	"context"
+
+	"github.com/harness/gitness/types"
+)
+
+// PluginChannelStore persists the sync health of configured plugin channels
+// so it survives restarts of the service.
+type PluginChannelStore interface {
+	// Find returns the sync status for a single channel URL.
+	Find(ctx context.Context, url string) (*types.PluginChannel, error)
+
+	// List returns the sync status of every known channel.
+	List(ctx context.Context) ([]*types.PluginChannel, error)
+
+	// Upsert creates or updates a channel's sync status.
+	Upsert(ctx context.Context, channel *types.PluginChannel) error
+}
@@ -0,0 +1,71 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package types
+
+// Plugin represents a plugin definition ingested from a schema source
+// (zip, registry, etc) that can be referenced from a pipeline YAML.
+type Plugin struct {
+	ID          int64  `db:"plugin_id"          json:"-"`
+	UID         string `db:"plugin_uid"         json:"uid"`
+	Version     string `db:"plugin_version"     json:"version"`
+	Description string `db:"plugin_description" json:"description"`
+	Type        string `db:"plugin_type"        json:"type"`
+	Spec        string `db:"plugin_spec"        json:"spec"`
+	Logo        string `db:"plugin_logo"        json:"logo,omitempty"`
+	// Digest is the content-addressable digest of the source artifact this
+	// plugin was ingested from (e.g. an OCI manifest digest), used to detect
+	// unchanged plugins without comparing spec/logo content.
+	Digest string `db:"plugin_digest" json:"digest,omitempty"`
+	// Privileges is the JSON-encoded PluginPrivileges declared by the plugin
+	// spec's `privileges:` block.
+	Privileges string `db:"plugin_privileges" json:"privileges,omitempty"`
+	// Checksum is the hex sha256 digest computed over the spec and
+	// privileges, i.e. the content a plugin signature is expected to cover.
+	Checksum string `db:"plugin_checksum" json:"checksum,omitempty"`
+	// Signature is the base64-encoded detached signature verified against
+	// Checksum at ingest time, set only once verification has succeeded.
+	Signature string `db:"plugin_signature" json:"-"`
+	Enabled   bool   `db:"plugin_enabled"   json:"enabled"`
+	Created   int64  `db:"plugin_created"   json:"created"`
+	Updated   int64  `db:"plugin_updated"   json:"updated"`
+}
+
+// Matches returns true if the two plugins carry the same content, ignoring
+// fields that are managed by the store (ID, Enabled, Created, Updated).
+func (p *Plugin) Matches(n *Plugin) bool {
+	return p.UID == n.UID &&
+		p.Version == n.Version &&
+		p.Type == n.Type &&
+		p.Spec == n.Spec &&
+		p.Logo == n.Logo &&
+		p.Privileges == n.Privileges
+}
+
+// PluginFilter stores plugin query parameters for filtering and pagination.
+type PluginFilter struct {
+	Query   string `json:"query"`
+	Page    int    `json:"page"`
+	Size    int    `json:"size"`
+	Enabled *bool  `json:"enabled,omitempty"`
+}
+
+// PluginPrivileges declares the runtime privileges a plugin step requires,
+// borrowed from the Docker plugin privileges model - the set of things an
+// operator should be asked to confirm before a pipeline is allowed to use it.
+type PluginPrivileges struct {
+	NetworkEgress []string `json:"network,omitempty" yaml:"network"`
+	Mounts        []string `json:"mounts,omitempty"  yaml:"mounts"`
+	Secrets       []string `json:"secrets,omitempty" yaml:"secrets"`
+	Env           []string `json:"env,omitempty"     yaml:"env"`
+	RunAsRoot     bool     `json:"root,omitempty"    yaml:"root"`
+}
+
+// PluginPrivilegeDiff captures how a plugin version's privileges changed
+// relative to another version, so a UI can prompt for confirmation before a
+// pipeline is allowed to reference the new version.
+type PluginPrivilegeDiff struct {
+	Added   PluginPrivileges `json:"added"`
+	Removed PluginPrivileges `json:"removed"`
+}
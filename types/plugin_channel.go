@@ -0,0 +1,19 @@
+// Copyright 2022 Harness Inc. All rights reserved.
+// Use of this source code is governed by the Polyform Free Trial License
+// that can be found in the LICENSE.md file for this repository.
+
+package types
+
+// PluginChannel tracks the sync state of a single configured plugin channel
+// - an index document enumerating the plugin packages available from it,
+// their versions, and where to download each one from.
+type PluginChannel struct {
+	ID           int64  `db:"plugin_channel_id"            json:"-"`
+	URL          string `db:"plugin_channel_url"           json:"url"`
+	ETag         string `db:"plugin_channel_etag"          json:"-"`
+	LastSyncedAt int64  `db:"plugin_channel_last_synced"   json:"last_synced,omitempty"`
+	LastError    string `db:"plugin_channel_last_error"    json:"last_error,omitempty"`
+	PluginCount  int    `db:"plugin_channel_plugin_count"  json:"plugin_count"`
+	Created      int64  `db:"plugin_channel_created"       json:"created"`
+	Updated      int64  `db:"plugin_channel_updated"       json:"updated"`
+}